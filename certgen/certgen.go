@@ -0,0 +1,248 @@
+// Package certgen implements a minimal certificate authority for minting
+// short-lived, per-SNI leaf certificates on demand — the same cache-backed
+// pattern mitmproxy-style tools use so a single installed root CA can cover
+// any number of intercepted hostnames.
+package certgen
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	caCertFile = "ca-cert.pem"
+	caKeyFile  = "ca-key.pem"
+)
+
+// NewECDSAKeyAndSerial generates a fresh P-256 private key and a random
+// 128-bit serial number, the two ingredients every certificate this package
+// mints (CA or leaf) is built from.
+func NewECDSAKeyAndSerial() (*ecdsa.PrivateKey, *big.Int, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, serialNumber, nil
+}
+
+// CA is a long-lived certificate authority used to mint short-lived leaf
+// certificates for individual hostnames on demand.
+type CA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	cert      *tls.Certificate
+	expiresAt time.Time
+}
+
+// NewCA loads a CA from dir if one was already persisted there, or
+// generates and persists a new one. leafTTL controls how long minted leaf
+// certificates stay cached before IssueLeaf re-mints them; a background
+// sweeper evicts expired cache entries every leafTTL/2.
+func NewCA(dir string, leafTTL time.Duration) (*CA, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating CA_DIR: %w", err)
+	}
+
+	cert, key, certPEM, err := loadOrCreateCA(filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	ca := &CA{
+		cert:    cert,
+		key:     key,
+		certPEM: certPEM,
+		ttl:     leafTTL,
+		cache:   make(map[string]*cacheEntry),
+	}
+
+	go ca.sweep()
+
+	return ca, nil
+}
+
+func loadOrCreateCA(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, []byte, error) {
+	if certPEM, err := os.ReadFile(certPath); err == nil {
+		if keyPEM, err := os.ReadFile(keyPath); err == nil {
+			if cert, key, ok := parseCA(certPEM, keyPEM); ok {
+				return cert, key, certPEM, nil
+			}
+		}
+	}
+	return generateCA(certPath, keyPath)
+}
+
+func parseCA(certPEM, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, bool) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, false
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, false
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, false
+	}
+	return cert, key, true
+}
+
+func generateCA(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, []byte, error) {
+	key, serialNumber, err := NewECDSAKeyAndSerial()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"quic-host MITM CA"},
+			CommonName:   "quic-host MITM CA",
+		},
+		NotBefore:             now,
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return cert, key, certPEM, nil
+}
+
+// PEM returns the CA certificate in PEM form, for serving at /ca.pem so
+// users can install it as a trusted root.
+func (ca *CA) PEM() []byte {
+	return ca.certPEM
+}
+
+// IssueLeaf returns a leaf certificate for host signed by the CA, minting
+// and caching a fresh one if none is cached or the cached one has expired.
+func (ca *CA) IssueLeaf(host string) (*tls.Certificate, error) {
+	ca.mu.Lock()
+	entry, ok := ca.cache[host]
+	ca.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.cert, nil
+	}
+
+	cert, err := ca.mintLeaf(host)
+	if err != nil {
+		return nil, err
+	}
+
+	ca.mu.Lock()
+	ca.cache[host] = &cacheEntry{cert: cert, expiresAt: time.Now().Add(ca.ttl)}
+	ca.mu.Unlock()
+
+	return cert, nil
+}
+
+func (ca *CA) mintLeaf(host string) (*tls.Certificate, error) {
+	key, serialNumber, err := NewECDSAKeyAndSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(ca.ttl + time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        template,
+	}, nil
+}
+
+// sweep periodically evicts expired leaf certificates from the cache.
+func (ca *CA) sweep() {
+	interval := ca.ttl / 2
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		ca.mu.Lock()
+		for host, entry := range ca.cache {
+			if now.After(entry.expiresAt) {
+				delete(ca.cache, host)
+			}
+		}
+		ca.mu.Unlock()
+	}
+}
@@ -0,0 +1,75 @@
+package certgen
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestNewCAPersistsAcrossReload(t *testing.T) {
+	dir := t.TempDir()
+
+	ca1, err := NewCA(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	ca2, err := NewCA(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("NewCA (reload): %v", err)
+	}
+
+	if string(ca1.PEM()) != string(ca2.PEM()) {
+		t.Errorf("reloading NewCA from the same dir produced a different CA cert instead of reusing the persisted one")
+	}
+}
+
+func TestIssueLeafCachesUntilExpiry(t *testing.T) {
+	ca, err := NewCA(t.TempDir(), 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	first, err := ca.IssueLeaf("example.com")
+	if err != nil {
+		t.Fatalf("IssueLeaf: %v", err)
+	}
+
+	second, err := ca.IssueLeaf("example.com")
+	if err != nil {
+		t.Fatalf("IssueLeaf (cached): %v", err)
+	}
+	if first.Leaf.SerialNumber.Cmp(second.Leaf.SerialNumber) != 0 {
+		t.Errorf("IssueLeaf minted a new certificate for %q before its TTL expired", "example.com")
+	}
+
+	time.Sleep(75 * time.Millisecond)
+
+	third, err := ca.IssueLeaf("example.com")
+	if err != nil {
+		t.Fatalf("IssueLeaf (after expiry): %v", err)
+	}
+	if first.Leaf.SerialNumber.Cmp(third.Leaf.SerialNumber) == 0 {
+		t.Errorf("IssueLeaf returned the expired certificate instead of minting a fresh one")
+	}
+}
+
+func TestIssueLeafSignedByCA(t *testing.T) {
+	ca, err := NewCA(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	leaf, err := ca.IssueLeaf("127.0.0.1")
+	if err != nil {
+		t.Fatalf("IssueLeaf: %v", err)
+	}
+
+	parsed, err := x509.ParseCertificate(leaf.Certificate[0])
+	if err != nil {
+		t.Fatalf("parsing minted leaf: %v", err)
+	}
+	if err := parsed.CheckSignatureFrom(ca.cert); err != nil {
+		t.Errorf("leaf certificate is not signed by the CA: %v", err)
+	}
+}
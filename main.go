@@ -1,8 +1,6 @@
 package main
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
@@ -10,15 +8,14 @@ import (
 	"embed"
 	"encoding/pem"
 	"log"
-	"math/big"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/idMdev/quic-host/certgen"
 	"github.com/quic-go/quic-go"
-	"github.com/quic-go/quic-go/http3"
 )
 
 //go:embed static/*
@@ -31,55 +28,55 @@ func main() {
 	}
 
 	// Setup TLS configuration
-	tlsConfig, err := generateTLSConfig()
+	tlsConfig, certSrc, err := generateTLSConfig()
 	if err != nil {
 		log.Fatal("Failed to generate TLS config:", err)
 	}
 
 	// Create HTTP handler
 	mux := http.NewServeMux()
-	
-	// Serve static files (HTML, CSS, JS)
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Request: %s %s (Protocol: %s)", r.Method, r.URL.Path, r.Proto)
-		
-		path := r.URL.Path
-		if path == "/" {
-			path = "/index.html"
-		}
-		
-		content, err := staticFiles.ReadFile("static" + path)
+
+	mux.HandleFunc("/v1/certificates", certificatesHandler(certSrc))
+	mux.Handle("/metrics", metricsHandler())
+	mux.HandleFunc("/debug/qlog", qlogDebugHandler())
+
+	go watchCertExpiry(certSrc)
+
+	// In proxy mode, "/" forwards to the configured upstream instead of
+	// serving static assets.
+	proxyHandler, err := setupProxy(mux, tlsConfig)
+	if err != nil {
+		log.Fatal("Failed to set up proxy:", err)
+	}
+
+	if proxyHandler != nil {
+		mux.Handle("/", proxyHandler)
+	} else {
+		// Serve static files (HTML, CSS, JS), with Range/ETag support and an
+		// optional on-disk overlay for development.
+		static, err := staticHandler(staticFiles)
 		if err != nil {
-			http.NotFound(w, r)
-			return
+			log.Fatal("Failed to set up static file handler:", err)
 		}
-		
-		// Set content type based on file extension
-		contentType := getContentType(path)
-		w.Header().Set("Content-Type", contentType)
-		
-		// Enable streaming for video files
-		if contentType == "video/mp4" {
-			w.Header().Set("Accept-Ranges", "bytes")
-		}
-		
-		w.WriteHeader(http.StatusOK)
-		w.Write(content)
-	})
+		mux.HandleFunc("/", static)
+	}
+
+	handler := loggingMiddleware(mux)
+
+	quicConfig := &quic.Config{
+		EnableDatagrams: true,
+		Tracer:          connectionTracer(),
+	}
+
+	// The WebTransport server IS the module's HTTP/3 server: it owns the
+	// http3.Server it upgrades sessions on, so it (not a second instance)
+	// is what gets started below.
+	wts := newWebTransportServer(":"+port, mux, handler, tlsConfig, quicConfig, NewWTHub())
 
 	// Start HTTP/3 (QUIC) server
 	go func() {
-		server := &http3.Server{
-			Addr:      ":" + port,
-			Handler:   mux,
-			TLSConfig: tlsConfig,
-			QuicConfig: &quic.Config{
-				EnableDatagrams: true,
-			},
-		}
-		
 		log.Printf("Starting HTTP/3 (QUIC) server on port %s", port)
-		if err := server.ListenAndServe(); err != nil {
+		if err := wts.ListenAndServe(); err != nil {
 			log.Fatal("HTTP/3 server error:", err)
 		}
 	}()
@@ -87,7 +84,7 @@ func main() {
 	// Start HTTP/2 and HTTP/1.1 fallback server
 	fallbackServer := &http.Server{
 		Addr:      ":" + port,
-		Handler:   mux,
+		Handler:   handler,
 		TLSConfig: tlsConfig,
 	}
 	
@@ -97,36 +94,6 @@ func main() {
 	}
 }
 
-func generateTLSConfig() (*tls.Config, error) {
-	// Check if certificate files exist
-	certFile := os.Getenv("TLS_CERT_FILE")
-	keyFile := os.Getenv("TLS_KEY_FILE")
-	
-	if certFile == "" {
-		certFile = "/certs/cert.pem"
-	}
-	if keyFile == "" {
-		keyFile = "/certs/key.pem"
-	}
-
-	// Try to load existing certificates
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-	if err != nil {
-		// If certificates don't exist, generate self-signed ones
-		log.Println("Using self-signed certificate (for testing only)")
-		cert, err = generateSelfSignedCert()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		NextProtos:   []string{"h3", "h2", "http/1.1"}, // HTTP/3, HTTP/2, HTTP/1.1
-		MinVersion:   tls.VersionTLS12,
-	}, nil
-}
-
 func generateSelfSignedCert() (tls.Certificate, error) {
 	certPEM, keyPEM, err := generateSelfSignedCertPEM()
 	if err != nil {
@@ -137,8 +104,9 @@ func generateSelfSignedCert() (tls.Certificate, error) {
 }
 
 func generateSelfSignedCertPEM() ([]byte, []byte, error) {
-	// Generate a new private key
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	// Generate a new private key and serial number, shared with the certgen
+	// package's CA/leaf minting so this boilerplate exists in one place.
+	priv, serialNumber, err := certgen.NewECDSAKeyAndSerial()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -147,11 +115,6 @@ func generateSelfSignedCertPEM() ([]byte, []byte, error) {
 	notBefore := time.Now()
 	notAfter := notBefore.Add(365 * 24 * time.Hour) // Valid for 1 year
 
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	if err != nil {
-		return nil, nil, err
-	}
-
 	template := x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quic_host_http_requests_total",
+		Help: "Total HTTP requests served, by negotiated protocol and status class.",
+	}, []string{"protocol", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "quic_host_http_request_duration_seconds",
+		Help:    "HTTP request duration in seconds, by negotiated protocol.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"protocol"})
+
+	httpResponseBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quic_host_http_response_bytes_total",
+		Help: "Total bytes written in HTTP responses, by negotiated protocol.",
+	}, []string{"protocol"})
+
+	activeQUICConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "quic_host_active_quic_connections",
+		Help: "Number of currently open QUIC connections.",
+	})
+
+	quicHandshakesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quic_host_quic_handshakes_total",
+		Help: "Completed QUIC handshakes, labelled by whether 0-RTT data was used.",
+	}, []string{"rtt"})
+
+	quicDatagramsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quic_host_quic_datagrams_total",
+		Help: "QUIC DATAGRAM frames seen, by direction.",
+	}, []string{"direction"})
+
+	certExpirySeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "quic_host_certificate_expiry_seconds",
+		Help: "Unix timestamp (seconds) at which the currently active leaf certificate expires.",
+	})
+)
+
+// protoLabel maps an http.Request.Proto string to a stable metric/log label.
+func protoLabel(proto string) string {
+	switch proto {
+	case "HTTP/3.0", "HTTP/3":
+		return "h3"
+	case "HTTP/2.0":
+		return "h2"
+	default:
+		return "http/1.1"
+	}
+}
+
+func statusClass(status int) string {
+	switch status / 100 {
+	case 2:
+		return "2xx"
+	case 3:
+		return "3xx"
+	case 4:
+		return "4xx"
+	case 5:
+		return "5xx"
+	default:
+		return "other"
+	}
+}
+
+// statusWriter wraps http.ResponseWriter to capture the status code and byte
+// count written, for logging and metrics.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap exposes the underlying ResponseWriter via http.ResponseController,
+// so wrapping with statusWriter doesn't hide capabilities callers further
+// down the chain need (e.g. the WebTransport upgrade on /wt/).
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// loggingMiddleware emits one structured JSON log line per request (method,
+// path, negotiated protocol, status, bytes, duration) and records the
+// request in the Prometheus metrics declared above.
+func loggingMiddleware(next http.Handler) http.Handler {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r)
+
+		duration := time.Since(start)
+		protocol := protoLabel(r.Proto)
+
+		httpRequestsTotal.WithLabelValues(protocol, statusClass(sw.status)).Inc()
+		httpRequestDuration.WithLabelValues(protocol).Observe(duration.Seconds())
+		httpResponseBytes.WithLabelValues(protocol).Add(float64(sw.bytes))
+
+		logger.Info("http_request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"protocol", protocol,
+			"status", sw.status,
+			"bytes", sw.bytes,
+			"duration_ms", duration.Milliseconds(),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// metricsHandler exposes the process's Prometheus registry at /metrics.
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// watchCertExpiry keeps certExpirySeconds in sync with whichever certificate
+// src currently reports as active, so the gauge reflects ACME renewals and
+// hot-reloaded keypairs alike.
+func watchCertExpiry(src certSource) {
+	update := func() {
+		cert, err := src.ActiveCertificate()
+		if err != nil {
+			return
+		}
+		notAfter, err := certNotAfter(cert)
+		if err != nil {
+			return
+		}
+		certExpirySeconds.Set(float64(notAfter.Unix()))
+	}
+
+	update()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		update()
+	}
+}
+
+// certNotAfter returns cert's expiry time, parsing the leaf DER if Leaf
+// wasn't already populated.
+func certNotAfter(cert *tls.Certificate) (time.Time, error) {
+	if cert.Leaf != nil {
+		return cert.Leaf.NotAfter, nil
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+	return leaf.NotAfter, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/idMdev/quic-host/certgen"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// proxyMode returns the configured PROXY_MODE ("mitm", "reverse" or "off"),
+// defaulting to "off".
+func proxyMode() string {
+	mode := os.Getenv("PROXY_MODE")
+	if mode == "" {
+		mode = "off"
+	}
+	return mode
+}
+
+// caLeafTTL returns the configured CA_LEAF_TTL (e.g. "24h", "30m"),
+// defaulting to 24h when unset or invalid.
+func caLeafTTL() time.Duration {
+	ttl := os.Getenv("CA_LEAF_TTL")
+	if ttl == "" {
+		return 24 * time.Hour
+	}
+
+	d, err := time.ParseDuration(ttl)
+	if err != nil {
+		log.Printf("invalid CA_LEAF_TTL %q, using 24h: %v", ttl, err)
+		return 24 * time.Hour
+	}
+	return d
+}
+
+// setupProxy wires up the forward/reverse proxy feature. For "mitm" it also
+// replaces tlsConfig.GetCertificate with one that mints a fresh per-SNI leaf
+// certificate from a local CA on demand, and registers /ca.pem on mux so
+// that CA can be installed by clients. It returns the handler to mount at
+// "/" in place of the static file handler, or nil when PROXY_MODE=off.
+func setupProxy(mux *http.ServeMux, tlsConfig *tls.Config) (http.Handler, error) {
+	mode := proxyMode()
+	if mode == "off" {
+		return nil, nil
+	}
+
+	upstream := os.Getenv("PROXY_UPSTREAM")
+	if upstream == "" {
+		return nil, fmt.Errorf("PROXY_MODE=%s requires PROXY_UPSTREAM", mode)
+	}
+	upstreamURL, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY_UPSTREAM: %w", err)
+	}
+
+	rp := httputil.NewSingleHostReverseProxy(upstreamURL)
+	rp.Transport = newFallbackTransport()
+
+	// NewSingleHostReverseProxy only rewrites the request URL, not the Host
+	// header; without this, origins that do Host-based vhosting or SNI
+	// routing see the original client Host instead of the upstream's.
+	director := rp.Director
+	rp.Director = func(req *http.Request) {
+		director(req)
+		req.Host = upstreamURL.Host
+	}
+
+	if mode == "mitm" {
+		caDir := os.Getenv("CA_DIR")
+		if caDir == "" {
+			caDir = "/var/lib/quic-host/ca"
+		}
+
+		ca, err := certgen.NewCA(caDir, caLeafTTL())
+		if err != nil {
+			return nil, fmt.Errorf("initializing MITM CA: %w", err)
+		}
+
+		tlsConfig.GetCertificate = func(chi *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			host := chi.ServerName
+			if host == "" {
+				host = upstreamURL.Hostname()
+			}
+			return ca.IssueLeaf(host)
+		}
+
+		mux.HandleFunc("/ca.pem", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-pem-file")
+			w.Write(ca.PEM())
+		})
+	}
+
+	log.Printf("Proxy mode %q active, forwarding to %s", mode, upstream)
+	return rp, nil
+}
+
+// fallbackTransport proxies over HTTP/3 to the upstream when possible and
+// falls back to the default (HTTP/2-capable) transport otherwise, since not
+// every origin speaks QUIC.
+type fallbackTransport struct {
+	h3       *http3.RoundTripper
+	fallback http.RoundTripper
+}
+
+func newFallbackTransport() *fallbackTransport {
+	return &fallbackTransport{
+		h3:       &http3.RoundTripper{},
+		fallback: http.DefaultTransport,
+	}
+}
+
+func (t *fallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.h3.RoundTrip(req)
+	if err == nil {
+		return resp, nil
+	}
+	log.Printf("proxy: HTTP/3 to upstream failed (%v), falling back to HTTP/2", err)
+	return t.fallback.RoundTrip(req)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/qlog"
+)
+
+// qlogEnabled gates whether connectionTracer writes qlog files, in addition
+// to always recording metrics. It starts from DEBUG_QLOG and can be flipped
+// at runtime via the /debug/qlog endpoint without a restart.
+var qlogEnabled atomic.Bool
+
+func init() {
+	qlogEnabled.Store(os.Getenv("DEBUG_QLOG") != "")
+}
+
+// qlogDir returns the configured qlog output directory (QLOG_DIR, defaulting
+// to "qlogs"). It does not create it; connectionTracer does that before
+// writing.
+func qlogDir() string {
+	dir := os.Getenv("QLOG_DIR")
+	if dir == "" {
+		dir = "qlogs"
+	}
+	return dir
+}
+
+// qlogDebugHandler reports and toggles qlogEnabled. GET reports the current
+// state, POST flips it.
+func qlogDebugHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			qlogEnabled.Store(!qlogEnabled.Load())
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		fmt.Fprintf(w, "qlog enabled: %v\n", qlogEnabled.Load())
+	}
+}
+
+// connectionTracer builds the quic.Config.Tracer hook that drives the
+// active-connection gauge and handshake/datagram counters for every QUIC
+// connection, and additionally writes a qlog file per connection while
+// qlogEnabled is set.
+func connectionTracer() func(ctx context.Context, p logging.Perspective, odcid quic.ConnectionID) *logging.ConnectionTracer {
+	return func(ctx context.Context, p logging.Perspective, odcid quic.ConnectionID) *logging.ConnectionTracer {
+		tracers := []*logging.ConnectionTracer{metricsConnectionTracer()}
+
+		if qlogEnabled.Load() {
+			if err := os.MkdirAll(qlogDir(), 0755); err != nil {
+				log.Printf("qlog: failed to create %s: %v", qlogDir(), err)
+			} else {
+				tracers = append(tracers, qlog.NewConnectionTracer(qlogWriter(odcid, p), p, odcid))
+			}
+		}
+
+		return logging.NewMultiplexedConnectionTracer(tracers...)
+	}
+}
+
+// metricsConnectionTracer reports connection lifecycle, handshake and
+// datagram events into the Prometheus metrics declared in observability.go.
+// Each call returns a tracer scoped to a single connection, so the 0-RTT
+// flag below is safe to keep as a plain closure variable. quicHandshakesTotal
+// is incremented from UpdatedKeyFromTLS when 1-RTT keys are installed, since
+// that's the point the handshake has actually completed; ClosedConnection
+// fires for every connection regardless of whether it ever got that far, so
+// counting there would also count timed-out/rejected handshakes as completed.
+func metricsConnectionTracer() *logging.ConnectionTracer {
+	var mu sync.Mutex
+	used0RTT := false
+	handshakeCounted := false
+
+	countDatagrams := func(direction string, frames []logging.Frame) {
+		for _, f := range frames {
+			if _, ok := f.(*logging.DatagramFrame); ok {
+				quicDatagramsTotal.WithLabelValues(direction).Inc()
+			}
+		}
+	}
+
+	return &logging.ConnectionTracer{
+		StartedConnection: func(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+			activeQUICConnections.Inc()
+		},
+		RestoredTransportParameters: func(parameters *logging.TransportParameters) {
+			mu.Lock()
+			used0RTT = true
+			mu.Unlock()
+		},
+		UpdatedKeyFromTLS: func(level logging.EncryptionLevel, perspective logging.Perspective) {
+			if level != logging.Encryption1RTT {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if handshakeCounted {
+				return
+			}
+			handshakeCounted = true
+
+			rtt := "1-rtt"
+			if used0RTT {
+				rtt = "0-rtt"
+			}
+			quicHandshakesTotal.WithLabelValues(rtt).Inc()
+		},
+		ClosedConnection: func(err error) {
+			activeQUICConnections.Dec()
+		},
+		SentShortHeaderPacket: func(hdr *logging.ShortHeader, size logging.ByteCount, ecn logging.ECN, ack *logging.AckFrame, frames []logging.Frame) {
+			countDatagrams("sent", frames)
+		},
+		ReceivedShortHeaderPacket: func(hdr *logging.ShortHeader, size logging.ByteCount, ecn logging.ECN, frames []logging.Frame) {
+			countDatagrams("received", frames)
+		},
+	}
+}
+
+func qlogWriter(odcid quic.ConnectionID, p logging.Perspective) io.WriteCloser {
+	role := "server"
+	if p == logging.PerspectiveClient {
+		role = "client"
+	}
+
+	path := filepath.Join(qlogDir(), fmt.Sprintf("%s_%s.qlog", odcid, role))
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("qlog: failed to create %s: %v", path, err)
+		return nopWriteCloser{io.Discard}
+	}
+	return f
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
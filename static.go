@@ -0,0 +1,106 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// staticHandler serves static assets with Range and ETag support via
+// http.ServeContent, instead of buffering whole files into memory.
+// If-Modified-Since is honoured only for files served from STATIC_DIR;
+// embedded files carry no modification time, so it is ignored for them.
+// When STATIC_DIR is set, a matching file on disk there takes
+// precedence over the embedded copy, so content can be hot-swapped during
+// development without rebuilding the binary.
+func staticHandler(embedded embed.FS) (http.HandlerFunc, error) {
+	sub, err := fs.Sub(embedded, "static")
+	if err != nil {
+		return nil, err
+	}
+	overlayDir := os.Getenv("STATIC_DIR")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqPath := r.URL.Path
+		if reqPath == "/" {
+			reqPath = "/index.html"
+		}
+		name := strings.TrimPrefix(reqPath, "/")
+
+		if overlayDir != "" {
+			if served := serveFromOverlay(w, r, overlayDir, name); served {
+				return
+			}
+		}
+
+		file, err := sub.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		seeker, ok := file.(io.ReadSeeker)
+		if !ok {
+			http.Error(w, "static asset is not seekable", http.StatusInternalServerError)
+			return
+		}
+
+		setCacheHeaders(w, name, info.Size())
+		http.ServeContent(w, r, name, info.ModTime(), seeker)
+	}, nil
+}
+
+// serveFromOverlay serves name from dir if it exists there, returning true
+// when it handled the request.
+func serveFromOverlay(w http.ResponseWriter, r *http.Request, dir, name string) bool {
+	diskPath := filepath.Join(dir, filepath.FromSlash(name))
+
+	f, err := os.Open(diskPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	setCacheHeaders(w, name, info.Size())
+	http.ServeContent(w, r, name, info.ModTime(), f)
+	return true
+}
+
+// setCacheHeaders sets the content type and a cache policy appropriate for
+// the asset kind. Embedded files carry no real modification time, so the
+// ETag (derived from name+size) is what lets clients and browsers revalidate
+// with If-None-Match. The ETag is weak, so it never satisfies If-Range: a
+// Range request carrying If-Range gets the whole file back.
+func setCacheHeaders(w http.ResponseWriter, name string, size int64) {
+	w.Header().Set("Content-Type", getContentType("/"+name))
+	w.Header().Set("ETag", fmt.Sprintf("W/%q", fmt.Sprintf("%s-%d", name, size)))
+
+	switch path.Ext(name) {
+	case ".mp4":
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	case ".html":
+		w.Header().Set("Cache-Control", "no-cache")
+	case ".css", ".js":
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	default:
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+}
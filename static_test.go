@@ -0,0 +1,75 @@
+package main
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//go:embed static
+var testStaticFiles embed.FS
+
+func TestStaticHandlerServesEmbedded(t *testing.T) {
+	handler, err := staticHandler(testStaticFiles)
+	if err != nil {
+		t.Fatalf("staticHandler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "quic-host") {
+		t.Errorf("body = %q, want it to contain %q", rec.Body.String(), "quic-host")
+	}
+}
+
+func TestStaticHandlerOverlayPrecedence(t *testing.T) {
+	overlayDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(overlayDir, "index.html"), []byte("overlay content"), 0644); err != nil {
+		t.Fatalf("writing overlay file: %v", err)
+	}
+
+	t.Setenv("STATIC_DIR", overlayDir)
+	handler, err := staticHandler(testStaticFiles)
+	if err != nil {
+		t.Fatalf("staticHandler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got, want := rec.Body.String(), "overlay content"; got != want {
+		t.Errorf("body = %q, want %q (overlay should take precedence over embedded copy)", got, want)
+	}
+}
+
+func TestStaticHandlerRangeRequest(t *testing.T) {
+	handler, err := staticHandler(testStaticFiles)
+	if err != nil {
+		t.Fatalf("staticHandler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	req.Header.Set("Range", "bytes=0-4")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusPartialContent)
+	}
+	if got, want := rec.Body.String(), "<!DOC"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certSource abstracts over the places an active leaf certificate can come
+// from (a static/hot-reloaded keypair on disk, or an autocert.Manager) so
+// that /v1/certificates can report whichever one is actually serving
+// traffic without the handler needing to know which mode is active. The
+// per-SNI leaves minted in PROXY_MODE=mitm are not covered: there
+// /v1/certificates still reports the certificate generateTLSConfig set up.
+type certSource interface {
+	ActiveCertificate() (*tls.Certificate, error)
+}
+
+// certStore holds the currently active certificate behind an atomic.Value
+// so that watchCertFiles can swap it out while servers are live, without
+// the http3.Server/http.Server TLSConfig ever needing to change.
+type certStore struct {
+	current atomic.Value // *tls.Certificate
+}
+
+func (s *certStore) Store(cert *tls.Certificate) {
+	s.current.Store(cert)
+}
+
+func (s *certStore) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := s.current.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, fmt.Errorf("no certificate loaded yet")
+	}
+	return cert, nil
+}
+
+func (s *certStore) ActiveCertificate() (*tls.Certificate, error) {
+	cert, _ := s.current.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, fmt.Errorf("no certificate loaded yet")
+	}
+	return cert, nil
+}
+
+// watchCertFiles polls certFile/keyFile for mtime changes and hot-swaps
+// store's certificate when they change, so a cert renewal on disk doesn't
+// require restarting the servers.
+func watchCertFiles(certFile, keyFile string, store *certStore) {
+	lastMod, err := latestModTime(certFile, keyFile)
+	if err != nil {
+		log.Printf("cert watcher: could not stat %s/%s: %v", certFile, keyFile, err)
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		modTime, err := latestModTime(certFile, keyFile)
+		if err != nil {
+			log.Printf("cert watcher: %v", err)
+			continue
+		}
+		if modTime.Equal(lastMod) {
+			continue
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.Printf("cert watcher: failed to load updated certificate: %v", err)
+			continue
+		}
+
+		store.Store(&cert)
+		lastMod = modTime
+		log.Printf("cert watcher: reloaded certificate from %s", certFile)
+	}
+}
+
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// acmeCertSource adapts an autocert.Manager to certSource by asking it for
+// the certificate of the first configured domain.
+type acmeCertSource struct {
+	mgr    *autocert.Manager
+	domain string
+}
+
+func (a *acmeCertSource) ActiveCertificate() (*tls.Certificate, error) {
+	return a.mgr.GetCertificate(&tls.ClientHelloInfo{ServerName: a.domain})
+}
+
+// setupACME builds a tls.Config backed by autocert for the domains listed in
+// ACME_DOMAINS, along with the HTTP-01 challenge handler that must be served
+// on port 80. It returns ok=false when ACME_DOMAINS is unset so callers can
+// fall back to the static/self-signed path.
+func setupACME() (tlsConfig *tls.Config, challengeHandler http.Handler, src certSource, ok bool, err error) {
+	domainsEnv := os.Getenv("ACME_DOMAINS")
+	if domainsEnv == "" {
+		return nil, nil, nil, false, nil
+	}
+
+	var domains []string
+	for _, d := range strings.Split(domainsEnv, ",") {
+		d = strings.TrimSpace(d)
+		if d != "" {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, nil, nil, false, fmt.Errorf("ACME_DOMAINS set but contained no domains")
+	}
+
+	cacheDir := os.Getenv("ACME_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = "/var/cache/quic-host/acme"
+	}
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return nil, nil, nil, false, fmt.Errorf("creating ACME_CACHE_DIR: %w", err)
+	}
+
+	mgr := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      os.Getenv("ACME_EMAIL"),
+	}
+	if dirURL := os.Getenv("ACME_DIRECTORY_URL"); dirURL != "" {
+		mgr.Client = &acme.Client{DirectoryURL: dirURL}
+	}
+
+	tlsConfig = &tls.Config{
+		GetCertificate: mgr.GetCertificate,
+		NextProtos:     []string{"h3", "h2", "http/1.1", acme.ALPNProto},
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	return tlsConfig, mgr.HTTPHandler(nil), &acmeCertSource{mgr: mgr, domain: domains[0]}, true, nil
+}
+
+// generateTLSConfig picks the active TLS mode: ACME (when ACME_DOMAINS is
+// set), otherwise a static keypair from TLS_CERT_FILE/TLS_KEY_FILE with
+// hot-reload, falling back to a self-signed certificate for local testing.
+// The returned certSource backs the /v1/certificates inspection endpoint.
+func generateTLSConfig() (*tls.Config, certSource, error) {
+	if tlsConfig, challengeHandler, src, ok, err := setupACME(); ok || err != nil {
+		if err != nil {
+			return nil, nil, err
+		}
+		go func() {
+			log.Println("Starting ACME HTTP-01 challenge server on port 80")
+			if err := http.ListenAndServe(":80", challengeHandler); err != nil {
+				log.Printf("ACME challenge server error: %v", err)
+			}
+		}()
+		return tlsConfig, src, nil
+	}
+
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+
+	if certFile == "" {
+		certFile = "/certs/cert.pem"
+	}
+	if keyFile == "" {
+		keyFile = "/certs/key.pem"
+	}
+
+	store := &certStore{}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		// If certificates don't exist, generate self-signed ones.
+		log.Println("Using self-signed certificate (for testing only)")
+		cert, err = generateSelfSignedCert()
+		if err != nil {
+			return nil, nil, err
+		}
+		store.Store(&cert)
+	} else {
+		store.Store(&cert)
+		go watchCertFiles(certFile, keyFile, store)
+	}
+
+	return &tls.Config{
+		GetCertificate: store.GetCertificate,
+		NextProtos:     []string{"h3", "h2", "http/1.1"},
+		MinVersion:     tls.VersionTLS12,
+	}, store, nil
+}
+
+// certificatesHandler serves the PEM of the currently active leaf
+// certificate for operational inspection, e.g. confirming an ACME renewal
+// or a hot-reloaded keypair actually took effect.
+func certificatesHandler(src certSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cert, err := src.ActiveCertificate()
+		if err != nil || len(cert.Certificate) == 0 {
+			http.Error(w, "no active certificate", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.WriteHeader(http.StatusOK)
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/webtransport-go"
+)
+
+// WTHub is a minimal pub/sub hub over WebTransport: every connected session
+// both publishes and subscribes, so messages sent as QUIC datagrams by one
+// client are relayed to every other connected client. It exists so the demo
+// page has something to talk to that exercises streams and datagrams, the
+// two QUIC-only capabilities http3 alone doesn't give you.
+type WTHub struct {
+	mu       sync.Mutex
+	sessions map[*webtransport.Session]struct{}
+}
+
+func NewWTHub() *WTHub {
+	return &WTHub{sessions: make(map[*webtransport.Session]struct{})}
+}
+
+// HandleSession upgrades r to a WebTransport session, subscribes it to the
+// hub, and relays both datagrams and incoming bidirectional streams until
+// the client disconnects.
+func (h *WTHub) HandleSession(wts *webtransport.Server, w http.ResponseWriter, r *http.Request) {
+	session, err := wts.Upgrade(w, r)
+	if err != nil {
+		log.Printf("webtransport: upgrade failed: %v", err)
+		http.Error(w, "webtransport upgrade failed", http.StatusInternalServerError)
+		return
+	}
+
+	h.Subscribe(session)
+	defer h.unsubscribe(session)
+
+	log.Printf("webtransport: session established from %s", r.RemoteAddr)
+
+	ctx := session.Context()
+	go h.relayStreams(ctx, session)
+
+	for {
+		msg, err := session.ReceiveDatagram(ctx)
+		if err != nil {
+			log.Printf("webtransport: session closed: %v", err)
+			return
+		}
+		h.Publish(ctx, msg, session)
+	}
+}
+
+// relayStreams echoes every bidirectional stream opened by session back to
+// its sender, so the demo page can exercise streams as well as datagrams.
+func (h *WTHub) relayStreams(ctx context.Context, session *webtransport.Session) {
+	for {
+		stream, err := session.AcceptStream(ctx)
+		if err != nil {
+			return
+		}
+		go func() {
+			defer stream.Close()
+			buf := make([]byte, 32*1024)
+			for {
+				n, err := stream.Read(buf)
+				if n > 0 {
+					if _, werr := stream.Write(buf[:n]); werr != nil {
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Subscribe registers session to receive future Publish calls.
+func (h *WTHub) Subscribe(session *webtransport.Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions[session] = struct{}{}
+}
+
+func (h *WTHub) unsubscribe(session *webtransport.Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions, session)
+}
+
+// Publish fans msg out as a datagram to every subscribed session other than
+// from (the sender, if any).
+func (h *WTHub) Publish(ctx context.Context, msg []byte, from *webtransport.Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.sessions {
+		if s == from {
+			continue
+		}
+		if err := s.SendDatagram(msg); err != nil {
+			log.Printf("webtransport: failed to publish to subscriber: %v", err)
+		}
+	}
+}
+
+// newWebTransportServer builds the webtransport.Server that serves as the
+// module's single HTTP/3 server. webtransport-go needs to own the
+// http3.Server it upgrades sessions on (it registers a StreamHijacker and
+// turns on datagrams during init), so callers must start the returned
+// server themselves via ListenAndServe rather than standing up a second,
+// separate http3.Server. It also mounts the hub's session handler on mux at
+// WT_PATH (default "/wt/").
+func newWebTransportServer(addr string, mux *http.ServeMux, handler http.Handler, tlsConfig *tls.Config, quicConfig *quic.Config, hub *WTHub) *webtransport.Server {
+	wtPath := os.Getenv("WT_PATH")
+	if wtPath == "" {
+		wtPath = "/wt/"
+	}
+
+	wts := &webtransport.Server{
+		H3: http3.Server{
+			Addr:       addr,
+			Handler:    handler,
+			TLSConfig:  tlsConfig,
+			QUICConfig: quicConfig,
+		},
+	}
+
+	mux.HandleFunc(wtPath, func(w http.ResponseWriter, r *http.Request) {
+		hub.HandleSession(wts, w, r)
+	})
+
+	log.Printf("WebTransport endpoint mounted at %s", wtPath)
+
+	return wts
+}